@@ -8,6 +8,7 @@
 package layers
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 
@@ -23,6 +24,11 @@ type EnumMetadata struct {
 	Name string
 	// LayerType is the layer type implied by the given enum.
 	LayerType gopacket.LayerType
+	// registered is set by the Register* functions below to distinguish a
+	// real registration from the default "unsupported value" decoder every
+	// slot is backfilled with, so Lookup* and the overwrite check in
+	// Register* itself aren't fooled by that backfill.
+	registered bool
 }
 
 // errorFunc returns a decoder that spits out a specific error message.
@@ -264,6 +270,16 @@ const (
 	Dot11TypeDataQOSCFAckPollNoData Dot11Type = 0x3e
 )
 
+// USBType is an enumeration of Linux USB pseudo-header transfer types.
+type USBType uint8
+
+const (
+	USBTypeControl     USBType = 2
+	USBTypeIsochronous USBType = 0
+	USBTypeBulk        USBType = 3
+	USBTypeInterrupt   USBType = 1
+)
+
 var (
 	// Each of the following arrays contains mappings of how to handle enum
 	// values for various enum types in gopacket/layers.
@@ -276,93 +292,355 @@ var (
 	// TCP decoder, you can override IPProtocolMetadata[IPProtocolTCP].DecodeWith
 	// with your new decoder, and all gopacket/layers decoding will use your new
 	// decoder whenever they encounter that IPProtocol.
-	EthernetTypeMetadata     EnumMetadata
-	IPProtocolMetadata       EnumMetadata
-	SCTPChunkTypeMetadata    EnumMetadata
-	PPPTypeMetadata          EnumMetadata
-	PPPoECodeMetadata        EnumMetadata
-	LinkTypeMetadata         EnumMetadata
-	FDDIFrameControlMetadata EnumMetadata
-	EAPOLTypeMetadata        EnumMetadata
-	ProtocolFamilyMetadata   EnumMetadata
-	Dot11TypeMetadata        EnumMetadata
-	USBTypeMetadata          EnumMetadata
+	//
+	// Third-party packages should not write these arrays directly; use
+	// RegisterEthernetType, RegisterIPProtocol, and friends below instead,
+	// which guard against accidentally clobbering an existing entry.
+	EthernetTypeMetadata     [65536]EnumMetadata
+	IPProtocolMetadata       [256]EnumMetadata
+	SCTPChunkTypeMetadata    [256]EnumMetadata
+	PPPTypeMetadata          [65536]EnumMetadata
+	PPPoECodeMetadata        [256]EnumMetadata
+	LinkTypeMetadata         [256]EnumMetadata
+	FDDIFrameControlMetadata [256]EnumMetadata
+	EAPOLTypeMetadata        [256]EnumMetadata
+	ProtocolFamilyMetadata   [256]EnumMetadata
+	Dot11TypeMetadata        [256]EnumMetadata
+	USBTypeMetadata          [256]EnumMetadata
 )
 
 func (a EthernetType) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return EthernetTypeMetadata.DecodeWith.Decode(data, p)
+	return EthernetTypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a EthernetType) String() string {
-	return EthernetTypeMetadata.Name
+	return EthernetTypeMetadata[a].Name
 }
 func (a EthernetType) LayerType() gopacket.LayerType {
-	return EthernetTypeMetadata.LayerType
+	return EthernetTypeMetadata[a].LayerType
 }
 func (a IPProtocol) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return IPProtocolMetadata.DecodeWith.Decode(data, p)
+	return IPProtocolMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a IPProtocol) String() string {
-	return IPProtocolMetadata.Name
+	return IPProtocolMetadata[a].Name
 }
 func (a IPProtocol) LayerType() gopacket.LayerType {
-	return IPProtocolMetadata.LayerType
+	return IPProtocolMetadata[a].LayerType
 }
 func (a SCTPChunkType) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return SCTPChunkTypeMetadata.DecodeWith.Decode(data, p)
+	return SCTPChunkTypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a SCTPChunkType) String() string {
-	return SCTPChunkTypeMetadata.Name
+	return SCTPChunkTypeMetadata[a].Name
 }
 func (a PPPType) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return PPPTypeMetadata.DecodeWith.Decode(data, p)
+	return PPPTypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a PPPType) String() string {
-	return PPPTypeMetadata.Name
+	return PPPTypeMetadata[a].Name
 }
 func (a LinkType) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return LinkTypeMetadata.DecodeWith.Decode(data, p)
+	return LinkTypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a LinkType) String() string {
-	return LinkTypeMetadata.Name
+	return LinkTypeMetadata[a].Name
 }
 func (a PPPoECode) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return PPPoECodeMetadata.DecodeWith.Decode(data, p)
+	return PPPoECodeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a PPPoECode) String() string {
-	return PPPoECodeMetadata.Name
+	return PPPoECodeMetadata[a].Name
 }
 func (a FDDIFrameControl) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return FDDIFrameControlMetadata.DecodeWith.Decode(data, p)
+	return FDDIFrameControlMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a FDDIFrameControl) String() string {
-	return FDDIFrameControlMetadata.Name
+	return FDDIFrameControlMetadata[a].Name
 }
 func (a EAPOLType) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return EAPOLTypeMetadata.DecodeWith.Decode(data, p)
+	return EAPOLTypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a EAPOLType) String() string {
-	return EAPOLTypeMetadata.Name
+	return EAPOLTypeMetadata[a].Name
 }
 func (a EAPOLType) LayerType() gopacket.LayerType {
-	return EAPOLTypeMetadata.LayerType
+	return EAPOLTypeMetadata[a].LayerType
 }
 func (a ProtocolFamily) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return ProtocolFamilyMetadata.DecodeWith.Decode(data, p)
+	return ProtocolFamilyMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a ProtocolFamily) String() string {
-	return ProtocolFamilyMetadata.Name
+	return ProtocolFamilyMetadata[a].Name
 }
 func (a ProtocolFamily) LayerType() gopacket.LayerType {
-	return ProtocolFamilyMetadata.LayerType
+	return ProtocolFamilyMetadata[a].LayerType
 }
 func (a Dot11Type) Decode(data []byte, p gopacket.PacketBuilder) error {
-	return Dot11TypeMetadata.DecodeWith.Decode(data, p)
+	return Dot11TypeMetadata[a].DecodeWith.Decode(data, p)
 }
 func (a Dot11Type) String() string {
-	return Dot11TypeMetadata.Name
+	return Dot11TypeMetadata[a].Name
 }
 func (a Dot11Type) LayerType() gopacket.LayerType {
-	return Dot11TypeMetadata.LayerType
+	return Dot11TypeMetadata[a].LayerType
+}
+
+// ErrEnumAlreadyRegistered is returned by the Register* functions below
+// when the requested enum value already has metadata registered and the
+// call did not set override.
+var ErrEnumAlreadyRegistered = errors.New("enum value already registered")
+
+// ErrEnumDecodeWithRequired is returned by the Register* functions below
+// when meta.DecodeWith is nil; every registered entry must have a decoder,
+// since that's what the corresponding Decode() method calls unconditionally.
+var ErrEnumDecodeWithRequired = errors.New("EnumMetadata.DecodeWith must not be nil")
+
+// RegisterEthernetType adds meta as the decoder/name/layer type for the
+// given EthernetType, for use by third-party packages that want to decode
+// an EtherType gopacket/layers doesn't know about (a private overlay,
+// Geneve, etc). It returns ErrEnumAlreadyRegistered if num already has
+// metadata registered, unless override is true. It's safe to call from an
+// external package's init function.
+func RegisterEthernetType(num EthernetType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && EthernetTypeMetadata[num].registered {
+		return fmt.Errorf("ethernet type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	EthernetTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupEthernetType returns the metadata registered for num, and whether
+// any decoder has been registered for it at all.
+func LookupEthernetType(num EthernetType) (EnumMetadata, bool) {
+	meta := EthernetTypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterIPProtocol adds meta as the decoder/name/layer type for the
+// given IPProtocol, for use by third-party packages that want to decode a
+// private or experimental IP protocol number. It returns
+// ErrEnumAlreadyRegistered if num already has metadata registered, unless
+// override is true.
+func RegisterIPProtocol(num IPProtocol, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && IPProtocolMetadata[num].registered {
+		return fmt.Errorf("IP protocol %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	IPProtocolMetadata[num] = meta
+	return nil
+}
+
+// LookupIPProtocol returns the metadata registered for num, and whether
+// any decoder has been registered for it at all.
+func LookupIPProtocol(num IPProtocol) (EnumMetadata, bool) {
+	meta := IPProtocolMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterSCTPChunkType adds meta as the decoder/name for the given
+// SCTPChunkType. It returns ErrEnumAlreadyRegistered if num already has
+// metadata registered, unless override is true.
+func RegisterSCTPChunkType(num SCTPChunkType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && SCTPChunkTypeMetadata[num].registered {
+		return fmt.Errorf("SCTP chunk type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	SCTPChunkTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupSCTPChunkType returns the metadata registered for num, and
+// whether any decoder has been registered for it at all.
+func LookupSCTPChunkType(num SCTPChunkType) (EnumMetadata, bool) {
+	meta := SCTPChunkTypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterPPPType adds meta as the decoder/name for the given PPPType. It
+// returns ErrEnumAlreadyRegistered if num already has metadata
+// registered, unless override is true.
+func RegisterPPPType(num PPPType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && PPPTypeMetadata[num].registered {
+		return fmt.Errorf("PPP type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	PPPTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupPPPType returns the metadata registered for num, and whether any
+// decoder has been registered for it at all.
+func LookupPPPType(num PPPType) (EnumMetadata, bool) {
+	meta := PPPTypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterPPPoECode adds meta as the decoder/name for the given
+// PPPoECode. It returns ErrEnumAlreadyRegistered if num already has
+// metadata registered, unless override is true.
+func RegisterPPPoECode(num PPPoECode, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && PPPoECodeMetadata[num].registered {
+		return fmt.Errorf("PPPoE code %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	PPPoECodeMetadata[num] = meta
+	return nil
+}
+
+// LookupPPPoECode returns the metadata registered for num, and whether
+// any decoder has been registered for it at all.
+func LookupPPPoECode(num PPPoECode) (EnumMetadata, bool) {
+	meta := PPPoECodeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterLinkType adds meta as the decoder/name for the given LinkType,
+// for use by third-party packages that want to decode a pcap linktype
+// gopacket/layers doesn't handle. It returns ErrEnumAlreadyRegistered if
+// num already has metadata registered, unless override is true.
+func RegisterLinkType(num LinkType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && LinkTypeMetadata[num].registered {
+		return fmt.Errorf("link type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	LinkTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupLinkType returns the metadata registered for num, and whether any
+// decoder has been registered for it at all.
+func LookupLinkType(num LinkType) (EnumMetadata, bool) {
+	meta := LinkTypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterFDDIFrameControl adds meta as the decoder/name for the given
+// FDDIFrameControl. It returns ErrEnumAlreadyRegistered if num already
+// has metadata registered, unless override is true.
+func RegisterFDDIFrameControl(num FDDIFrameControl, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && FDDIFrameControlMetadata[num].registered {
+		return fmt.Errorf("FDDI frame control %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	FDDIFrameControlMetadata[num] = meta
+	return nil
+}
+
+// LookupFDDIFrameControl returns the metadata registered for num, and
+// whether any decoder has been registered for it at all.
+func LookupFDDIFrameControl(num FDDIFrameControl) (EnumMetadata, bool) {
+	meta := FDDIFrameControlMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterEAPOLType adds meta as the decoder/name/layer type for the
+// given EAPOLType. It returns ErrEnumAlreadyRegistered if num already has
+// metadata registered, unless override is true.
+func RegisterEAPOLType(num EAPOLType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && EAPOLTypeMetadata[num].registered {
+		return fmt.Errorf("EAPOL type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	EAPOLTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupEAPOLType returns the metadata registered for num, and whether
+// any decoder has been registered for it at all.
+func LookupEAPOLType(num EAPOLType) (EnumMetadata, bool) {
+	meta := EAPOLTypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterProtocolFamily adds meta as the decoder/name/layer type for the
+// given ProtocolFamily. It returns ErrEnumAlreadyRegistered if num
+// already has metadata registered, unless override is true.
+func RegisterProtocolFamily(num ProtocolFamily, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && ProtocolFamilyMetadata[num].registered {
+		return fmt.Errorf("protocol family %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	ProtocolFamilyMetadata[num] = meta
+	return nil
+}
+
+// LookupProtocolFamily returns the metadata registered for num, and
+// whether any decoder has been registered for it at all.
+func LookupProtocolFamily(num ProtocolFamily) (EnumMetadata, bool) {
+	meta := ProtocolFamilyMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterDot11Type adds meta as the decoder/name/layer type for the
+// given Dot11Type. It returns ErrEnumAlreadyRegistered if num already has
+// metadata registered, unless override is true.
+func RegisterDot11Type(num Dot11Type, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && Dot11TypeMetadata[num].registered {
+		return fmt.Errorf("Dot11 type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	Dot11TypeMetadata[num] = meta
+	return nil
+}
+
+// LookupDot11Type returns the metadata registered for num, and whether
+// any decoder has been registered for it at all.
+func LookupDot11Type(num Dot11Type) (EnumMetadata, bool) {
+	meta := Dot11TypeMetadata[num]
+	return meta, meta.registered
+}
+
+// RegisterUSBType adds meta as the decoder/name for the given USBType. It
+// returns ErrEnumAlreadyRegistered if num already has metadata
+// registered, unless override is true.
+func RegisterUSBType(num USBType, meta EnumMetadata, override bool) error {
+	if meta.DecodeWith == nil {
+		return ErrEnumDecodeWithRequired
+	}
+	if !override && USBTypeMetadata[num].registered {
+		return fmt.Errorf("USB type %d: %w", num, ErrEnumAlreadyRegistered)
+	}
+	meta.registered = true
+	USBTypeMetadata[num] = meta
+	return nil
+}
+
+// LookupUSBType returns the metadata registered for num, and whether any
+// decoder has been registered for it at all.
+func LookupUSBType(num USBType) (EnumMetadata, bool) {
+	meta := USBTypeMetadata[num]
+	return meta, meta.registered
 }
 
 // Decode a raw v4 or v6 IP packet.
@@ -377,9 +655,90 @@ func decodeIPv4or6(data []byte, p gopacket.PacketBuilder) error {
 	return fmt.Errorf("Invalid IP packet version %v", version)
 }
 
+// decodeLoopbackHeader decodes the 4-byte BSD loopback header shared by
+// LinkTypeNull and LinkTypeLoop: a ProtocolFamily value, in the byte order
+// given by order, followed by the IPv4 or IPv6 payload it identifies.
+func decodeLoopbackHeader(data []byte, order binary.ByteOrder, p gopacket.PacketBuilder) error {
+	if len(data) < 4 {
+		return errors.New("Null/Loop packet too small")
+	}
+	family := ProtocolFamily(order.Uint32(data))
+	switch family {
+	case ProtocolFamilyIPv4:
+		return decodeIPv4(data[4:], p)
+	case ProtocolFamilyIPv6BSD, ProtocolFamilyIPv6FreeBSD, ProtocolFamilyIPv6Darwin, ProtocolFamilyIPv6Linux:
+		return decodeIPv6(data[4:], p)
+	}
+	return fmt.Errorf("Unsupported Null/Loop protocol family %v", family)
+}
+
+// decodeNull decodes LinkTypeNull (DLT_NULL, as produced by e.g.
+// "tcpdump -i lo0" on macOS). Its 4-byte protocol family header is in the
+// byte order of the host that wrote the capture; there's no portable way
+// to recover which byte order that was from the capture alone, so -- like
+// tcpdump -- we assume the common case of a little-endian host.
+func decodeNull(data []byte, p gopacket.PacketBuilder) error {
+	return decodeLoopbackHeader(data, binary.LittleEndian, p)
+}
+
+// decodeLoop decodes LinkTypeLoop (DLT_LOOP, the OpenBSD/NetBSD loopback
+// type). It's identical to LinkTypeNull except that, by convention, its
+// 4-byte protocol family header is always big-endian regardless of the
+// capturing host.
+func decodeLoop(data []byte, p gopacket.PacketBuilder) error {
+	return decodeLoopbackHeader(data, binary.BigEndian, p)
+}
+
 func init() {
-	// Here we link up all enumerations with their respective names and decoders.
-	EthernetTypeMetadata = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeIPv4), Name: "IPv4", LayerType: LayerTypeIPv4}
-	IPProtocolMetadata = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeUDP), Name: "UDP", LayerType: LayerTypeUDP}
+	// Backfill every slot with a decoder that reports the value as
+	// unsupported, so that decoding an enumeration value nobody has
+	// registered a real decoder for returns an error instead of a
+	// nil-pointer panic.
+	for i := range EthernetTypeMetadata {
+		EthernetTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported ethernet type: %d", i))}
+	}
+	for i := range IPProtocolMetadata {
+		IPProtocolMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported IP protocol: %d", i))}
+	}
+	for i := range SCTPChunkTypeMetadata {
+		SCTPChunkTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported SCTP chunk type: %d", i))}
+	}
+	for i := range PPPTypeMetadata {
+		PPPTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported PPP type: %d", i))}
+	}
+	for i := range PPPoECodeMetadata {
+		PPPoECodeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported PPPoE code: %d", i))}
+	}
+	for i := range LinkTypeMetadata {
+		LinkTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported link type: %d", i))}
+	}
+	for i := range FDDIFrameControlMetadata {
+		FDDIFrameControlMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported FDDI frame control: %d", i))}
+	}
+	for i := range EAPOLTypeMetadata {
+		EAPOLTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported EAPOL type: %d", i))}
+	}
+	for i := range ProtocolFamilyMetadata {
+		ProtocolFamilyMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported protocol family: %d", i))}
+	}
+	for i := range Dot11TypeMetadata {
+		Dot11TypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported Dot11 type: %d", i))}
+	}
+	for i := range USBTypeMetadata {
+		USBTypeMetadata[i] = EnumMetadata{DecodeWith: errorFunc(fmt.Sprintf("Unsupported USB type: %d", i))}
+	}
 
+	// Here we link up all enumerations with their respective names and decoders.
+	EthernetTypeMetadata[EthernetTypeIPv4] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeIPv4), Name: "IPv4", LayerType: LayerTypeIPv4, registered: true}
+	IPProtocolMetadata[IPProtocolUDP] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeUDP), Name: "UDP", LayerType: LayerTypeUDP, registered: true}
+
+	// LinkTypeRaw carries a version-dispatched IPv4-or-IPv6 payload, and
+	// LinkTypeIPv4/LinkTypeIPv6 a bare IPv4 or IPv6 one, directly, with no
+	// link-layer header at all, as seen on BSD/pflog captures and many
+	// userspace TUN tunnels.
+	LinkTypeMetadata[LinkTypeRaw] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeIPv4or6), Name: "Raw", registered: true}
+	LinkTypeMetadata[LinkTypeIPv4] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeIPv4), Name: "IPv4", LayerType: LayerTypeIPv4, registered: true}
+	LinkTypeMetadata[LinkTypeIPv6] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeIPv6), Name: "IPv6", LayerType: LayerTypeIPv6, registered: true}
+	LinkTypeMetadata[LinkTypeNull] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeNull), Name: "Null", registered: true}
+	LinkTypeMetadata[LinkTypeLoop] = EnumMetadata{DecodeWith: gopacket.DecodeFunc(decodeLoop), Name: "Loop", registered: true}
 }
@@ -0,0 +1,230 @@
+// Copyright 2012 Google, Inc. All rights reserved.
+// Copyright 2009-2011 Andreas Krennmair. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jamesfcarter/gopacket"
+)
+
+func noopDecoder() gopacket.Decoder {
+	return gopacket.DecodeFunc(func([]byte, gopacket.PacketBuilder) error { return nil })
+}
+
+func TestRegisterEthernetTypeOverwriteSemantics(t *testing.T) {
+	const num = EthernetType(0xFEFE)
+	defer func() { EthernetTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterEthernetType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterEthernetType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterEthernetType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, true); err != nil {
+		t.Fatalf("re-registering with override should succeed: %v", err)
+	}
+
+	got, ok := LookupEthernetType(num)
+	if !ok || got.Name != "test-b" {
+		t.Fatalf("unexpected lookup result: %+v, ok=%v", got, ok)
+	}
+}
+
+func TestRegisterEthernetTypeRequiresDecodeWith(t *testing.T) {
+	const num = EthernetType(0xFDFD)
+	if err := RegisterEthernetType(num, EnumMetadata{Name: "no-decoder"}, false); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+}
+
+func TestLookupEthernetTypeReportsUnregistered(t *testing.T) {
+	if _, ok := LookupEthernetType(EthernetType(0xFCFC)); ok {
+		t.Fatal("expected an unregistered ethernet type to report ok=false")
+	}
+}
+
+func TestDecodeOfUnregisteredEnumValueReturnsErrorNotPanic(t *testing.T) {
+	const unregistered = IPProtocol(253) // reserved for experimentation/testing, RFC 3692
+	if err := unregistered.Decode(nil, nil); err == nil {
+		t.Fatal("expected decoding an unregistered IPProtocol to return an error")
+	}
+}
+
+func TestRegisterSCTPChunkTypeOverwriteSemantics(t *testing.T) {
+	const num = SCTPChunkType(0xFE)
+	defer func() { SCTPChunkTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterSCTPChunkType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterSCTPChunkType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterSCTPChunkType(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupSCTPChunkType(SCTPChunkType(0xFD)); ok {
+		t.Fatal("expected an unregistered SCTPChunkType to report ok=false")
+	}
+}
+
+func TestRegisterPPPTypeOverwriteSemantics(t *testing.T) {
+	const num = PPPType(0xFEFE)
+	defer func() { PPPTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterPPPType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterPPPType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterPPPType(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupPPPType(PPPType(0xFDFD)); ok {
+		t.Fatal("expected an unregistered PPPType to report ok=false")
+	}
+}
+
+func TestRegisterPPPoECodeOverwriteSemantics(t *testing.T) {
+	const num = PPPoECode(0xFE)
+	defer func() { PPPoECodeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterPPPoECode(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterPPPoECode(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterPPPoECode(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupPPPoECode(PPPoECode(0xFD)); ok {
+		t.Fatal("expected an unregistered PPPoECode to report ok=false")
+	}
+}
+
+func TestRegisterLinkTypeOverwriteSemantics(t *testing.T) {
+	const num = LinkType(250)
+	defer func() { LinkTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterLinkType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterLinkType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterLinkType(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupLinkType(LinkType(249)); ok {
+		t.Fatal("expected an unregistered LinkType to report ok=false")
+	}
+}
+
+func TestRegisterFDDIFrameControlOverwriteSemantics(t *testing.T) {
+	const num = FDDIFrameControl(0xFE)
+	defer func() { FDDIFrameControlMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterFDDIFrameControl(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterFDDIFrameControl(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterFDDIFrameControl(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupFDDIFrameControl(FDDIFrameControl(0xFD)); ok {
+		t.Fatal("expected an unregistered FDDIFrameControl to report ok=false")
+	}
+}
+
+func TestRegisterEAPOLTypeOverwriteSemantics(t *testing.T) {
+	const num = EAPOLType(0xFE)
+	defer func() { EAPOLTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterEAPOLType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterEAPOLType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterEAPOLType(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupEAPOLType(EAPOLType(0xFD)); ok {
+		t.Fatal("expected an unregistered EAPOLType to report ok=false")
+	}
+}
+
+func TestRegisterProtocolFamilyOverwriteSemantics(t *testing.T) {
+	const num = ProtocolFamily(0xFE)
+	defer func() { ProtocolFamilyMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterProtocolFamily(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterProtocolFamily(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterProtocolFamily(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupProtocolFamily(ProtocolFamily(0xFD)); ok {
+		t.Fatal("expected an unregistered ProtocolFamily to report ok=false")
+	}
+}
+
+func TestRegisterDot11TypeOverwriteSemantics(t *testing.T) {
+	const num = Dot11Type(0xFE)
+	defer func() { Dot11TypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterDot11Type(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterDot11Type(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterDot11Type(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupDot11Type(Dot11Type(0xFD)); ok {
+		t.Fatal("expected an unregistered Dot11Type to report ok=false")
+	}
+}
+
+func TestRegisterUSBTypeOverwriteSemantics(t *testing.T) {
+	const num = USBType(0xFE)
+	defer func() { USBTypeMetadata[num] = EnumMetadata{} }()
+
+	if err := RegisterUSBType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-a"}, false); err != nil {
+		t.Fatalf("first registration should succeed: %v", err)
+	}
+	if err := RegisterUSBType(num, EnumMetadata{DecodeWith: noopDecoder(), Name: "test-b"}, false); !errors.Is(err, ErrEnumAlreadyRegistered) {
+		t.Fatalf("expected ErrEnumAlreadyRegistered re-registering without override, got %v", err)
+	}
+	if err := RegisterUSBType(num, EnumMetadata{Name: "no-decoder"}, true); !errors.Is(err, ErrEnumDecodeWithRequired) {
+		t.Fatalf("expected ErrEnumDecodeWithRequired, got %v", err)
+	}
+
+	if _, ok := LookupUSBType(USBType(0xFD)); ok {
+		t.Fatal("expected an unregistered USBType to report ok=false")
+	}
+}
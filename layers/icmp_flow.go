@@ -0,0 +1,240 @@
+// Copyright 2012 Google, Inc. All rights reserved.
+// Copyright 2009-2011 Andreas Krennmair. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"container/list"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jamesfcarter/gopacket"
+)
+
+// EndpointICMPv4Echo and EndpointICMPv6Echo identify the ICMP identifier
+// used to correlate an echo request with its reply, playing the same role
+// in a gopacket.Flow that a TCP/UDP port plays for those transports.
+var (
+	EndpointICMPv4Echo = gopacket.RegisterEndpointType(9001, gopacket.EndpointTypeMetadata{
+		Name:      "ICMPv4Echo",
+		Formatter: formatICMPEcho,
+	})
+	EndpointICMPv6Echo = gopacket.RegisterEndpointType(9002, gopacket.EndpointTypeMetadata{
+		Name:      "ICMPv6Echo",
+		Formatter: formatICMPEcho,
+	})
+)
+
+func formatICMPEcho(b []byte) string {
+	if len(b) != 2 {
+		return "?"
+	}
+	return fmt.Sprintf("%d", binary.BigEndian.Uint16(b))
+}
+
+// ICMPFlow is a single matched ICMP echo request/reply round trip, as
+// produced by ICMPFlowTracker.Observe.
+type ICMPFlow struct {
+	// Network is the IP flow between the two hosts, in the same direction
+	// as the original echo request (request's source to request's
+	// destination).
+	Network gopacket.Flow
+	// Echo is a degenerate flow over EndpointICMPv4Echo/EndpointICMPv6Echo
+	// keyed on the ICMP identifier, so that flow-aware code which keys off
+	// gopacket.Flow (reassembly, pcap filtering helpers) can treat it the
+	// same way it treats a TCP/UDP transport flow.
+	Echo       gopacket.Flow
+	Identifier uint16
+	Sequence   uint16
+	RTT        time.Duration
+}
+
+type icmpFlowKey struct {
+	request gopacket.Flow
+	id      uint16
+}
+
+type icmpPendingEcho struct {
+	key       icmpFlowKey
+	firstSeen time.Time
+	sequence  uint16
+}
+
+// ICMPFlowTracker matches ICMP echo requests with their replies -- and,
+// where possible, with the Destination Unreachable/Time Exceeded errors
+// reported against them -- producing an ICMPFlow per completed round trip.
+// This is the ICMP analog of the flow tracking TCP/UDP get for free from
+// their port numbers.
+//
+// The zero value is not usable; create one with NewICMPFlowTracker. An
+// ICMPFlowTracker is safe for concurrent use by multiple goroutines.
+type ICMPFlowTracker struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	pending map[icmpFlowKey]*list.Element
+	// order holds the same pending requests as icmpPendingEcho values,
+	// oldest first, so evictLocked can drop expired entries off the front
+	// in amortized O(1) instead of scanning the whole map -- timeout is
+	// constant, so insertion order and expiry order coincide.
+	order *list.List
+}
+
+// defaultICMPFlowTimeout is how long an unmatched echo request is kept
+// before it's evicted, if NewICMPFlowTracker is given a timeout <= 0.
+const defaultICMPFlowTimeout = 30 * time.Second
+
+// NewICMPFlowTracker creates an ICMPFlowTracker that discards unmatched
+// echo requests older than timeout. A timeout <= 0 uses a default of 30
+// seconds.
+func NewICMPFlowTracker(timeout time.Duration) *ICMPFlowTracker {
+	if timeout <= 0 {
+		timeout = defaultICMPFlowTimeout
+	}
+	return &ICMPFlowTracker{
+		timeout: timeout,
+		pending: make(map[icmpFlowKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Observe inspects packet for an ICMPv4 or ICMPv6 echo request, reply, or
+// Destination Unreachable/Time Exceeded error, and updates the tracker's
+// state accordingly. It returns the completed flow and true once both
+// halves of a round trip have been seen; packets that don't complete a
+// round trip (a fresh request, or anything that isn't ICMP echo-related)
+// return a zero ICMPFlow and false.
+func (t *ICMPFlowTracker) Observe(packet gopacket.Packet) (flow ICMPFlow, matched bool) {
+	net := packet.NetworkLayer()
+	if net == nil {
+		return ICMPFlow{}, false
+	}
+	if icmp4, ok := packet.Layer(LayerTypeICMPv4).(*ICMPv4); ok {
+		return t.observeV4(icmp4, net.NetworkFlow())
+	}
+	if icmp6, ok := packet.Layer(LayerTypeICMPv6).(*ICMPv6); ok {
+		return t.observeV6(packet, icmp6, net.NetworkFlow())
+	}
+	return ICMPFlow{}, false
+}
+
+func (t *ICMPFlowTracker) observeV4(icmp *ICMPv4, netFlow gopacket.Flow) (ICMPFlow, bool) {
+	switch icmp.TypeCode.Type() {
+	case ICMPv4TypeEchoRequest:
+		t.storeRequest(netFlow, icmp.Id, icmp.Seq)
+	case ICMPv4TypeEchoReply:
+		return t.matchReply(netFlow.Reverse(), icmp.Id, icmp.Seq, EndpointICMPv4Echo)
+	case ICMPv4TypeDestinationUnreachable, ICMPv4TypeTimeExceeded:
+		return t.observeEmbedded(icmp.Payload, LayerTypeIPv4)
+	}
+	return ICMPFlow{}, false
+}
+
+func (t *ICMPFlowTracker) observeV6(packet gopacket.Packet, icmp *ICMPv6, netFlow gopacket.Flow) (ICMPFlow, bool) {
+	switch icmp.TypeCode.Type() {
+	case ICMPv6TypeEchoRequest, ICMPv6TypeEchoReply:
+		echo, ok := packet.Layer(LayerTypeICMPv6Echo).(*ICMPv6Echo)
+		if !ok {
+			return ICMPFlow{}, false
+		}
+		if icmp.TypeCode.Type() == ICMPv6TypeEchoRequest {
+			t.storeRequest(netFlow, echo.Identifier, echo.SeqNumber)
+			return ICMPFlow{}, false
+		}
+		return t.matchReply(netFlow.Reverse(), echo.Identifier, echo.SeqNumber, EndpointICMPv6Echo)
+	case ICMPv6TypeDestinationUnreachable, ICMPv6TypeTimeExceeded:
+		// The ICMPv6 layer's payload starts with the 4-byte unused/MTU
+		// field; the invoking packet follows it.
+		if len(icmp.Payload) < 4 {
+			return ICMPFlow{}, false
+		}
+		return t.observeEmbedded(icmp.Payload[4:], LayerTypeIPv6)
+	}
+	return ICMPFlow{}, false
+}
+
+// observeEmbedded handles a Destination Unreachable/Time Exceeded error by
+// decoding the original IP+ICMP header it carries and resolving the flow
+// that original packet belongs to.
+func (t *ICMPFlowTracker) observeEmbedded(payload []byte, embeddedType gopacket.LayerType) (ICMPFlow, bool) {
+	embedded := gopacket.NewPacket(payload, embeddedType, gopacket.NoCopy)
+	net := embedded.NetworkLayer()
+	if net == nil {
+		return ICMPFlow{}, false
+	}
+	if icmp4, ok := embedded.Layer(LayerTypeICMPv4).(*ICMPv4); ok {
+		return t.matchReply(net.NetworkFlow(), icmp4.Id, icmp4.Seq, EndpointICMPv4Echo)
+	}
+	if echo, ok := embedded.Layer(LayerTypeICMPv6Echo).(*ICMPv6Echo); ok {
+		return t.matchReply(net.NetworkFlow(), echo.Identifier, echo.SeqNumber, EndpointICMPv6Echo)
+	}
+	return ICMPFlow{}, false
+}
+
+func (t *ICMPFlowTracker) storeRequest(netFlow gopacket.Flow, id, seq uint16) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.evictLocked()
+	key := icmpFlowKey{request: netFlow, id: id}
+	if el, ok := t.pending[key]; ok {
+		t.order.Remove(el)
+	}
+	t.pending[key] = t.order.PushBack(icmpPendingEcho{key: key, firstSeen: time.Now(), sequence: seq})
+}
+
+// matchReply looks up the pending request matching (requestFlow, id) --
+// requestFlow must already be in the original request's direction, i.e.
+// request-source to request-destination, and seq must match the sequence
+// number of the original request -- and, if found, consumes it and
+// returns the completed ICMPFlow.
+func (t *ICMPFlowTracker) matchReply(requestFlow gopacket.Flow, id, seq uint16, endpoint gopacket.EndpointType) (ICMPFlow, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.evictLocked()
+	key := icmpFlowKey{request: requestFlow, id: id}
+	el, ok := t.pending[key]
+	if !ok {
+		return ICMPFlow{}, false
+	}
+	pending := el.Value.(icmpPendingEcho)
+	if pending.sequence != seq {
+		return ICMPFlow{}, false
+	}
+	t.order.Remove(el)
+	delete(t.pending, key)
+
+	idBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(idBytes, id)
+	return ICMPFlow{
+		Network:    requestFlow,
+		Echo:       gopacket.NewFlow(endpoint, idBytes, idBytes),
+		Identifier: id,
+		Sequence:   pending.sequence,
+		RTT:        time.Since(pending.firstSeen),
+	}, true
+}
+
+// evictLocked drops pending requests older than t.timeout. Since t.order
+// holds them oldest-first and the timeout is constant, it only ever needs
+// to trim the front of the list, stopping at the first entry that's still
+// live rather than scanning every pending request. Called with t.mu held.
+func (t *ICMPFlowTracker) evictLocked() {
+	cutoff := time.Now().Add(-t.timeout)
+	for {
+		front := t.order.Front()
+		if front == nil {
+			return
+		}
+		pending := front.Value.(icmpPendingEcho)
+		if !pending.firstSeen.Before(cutoff) {
+			return
+		}
+		t.order.Remove(front)
+		delete(t.pending, pending.key)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2012 Google, Inc. All rights reserved.
+// Copyright 2009-2011 Andreas Krennmair. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jamesfcarter/gopacket"
+)
+
+func buildV4Echo(t *testing.T, src, dst net.IP, typ uint8, id, seq uint16) gopacket.Packet {
+	t.Helper()
+	ip := &IPv4{Version: 4, TTL: 64, Protocol: IPProtocolICMPv4, SrcIP: src, DstIP: dst}
+	icmp := &ICMPv4{TypeCode: CreateICMPv4TypeCode(typ, 0), Id: id, Seq: seq}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ip, icmp); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	data := make([]byte, len(buf.Bytes()))
+	copy(data, buf.Bytes())
+	return gopacket.NewPacket(data, LayerTypeIPv4, gopacket.Default)
+}
+
+func TestICMPFlowTrackerMatchesEchoReply(t *testing.T) {
+	tracker := NewICMPFlowTracker(time.Second)
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+
+	if _, matched := tracker.Observe(buildV4Echo(t, src, dst, ICMPv4TypeEchoRequest, 42, 1)); matched {
+		t.Fatal("echo request should not complete a flow by itself")
+	}
+
+	flow, matched := tracker.Observe(buildV4Echo(t, dst, src, ICMPv4TypeEchoReply, 42, 1))
+	if !matched {
+		t.Fatal("expected echo reply to complete the flow")
+	}
+	if flow.Identifier != 42 || flow.Sequence != 1 {
+		t.Fatalf("unexpected flow %+v", flow)
+	}
+	if flow.RTT < 0 {
+		t.Fatalf("unexpected negative RTT %v", flow.RTT)
+	}
+}
+
+func TestICMPFlowTrackerRejectsSequenceMismatch(t *testing.T) {
+	tracker := NewICMPFlowTracker(time.Second)
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+
+	tracker.Observe(buildV4Echo(t, src, dst, ICMPv4TypeEchoRequest, 42, 1))
+
+	if _, matched := tracker.Observe(buildV4Echo(t, dst, src, ICMPv4TypeEchoReply, 42, 2)); matched {
+		t.Fatal("reply with a mismatched sequence number should not match")
+	}
+}
+
+func TestICMPFlowTrackerEvictsStaleRequests(t *testing.T) {
+	tracker := NewICMPFlowTracker(10 * time.Millisecond)
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+
+	tracker.Observe(buildV4Echo(t, src, dst, ICMPv4TypeEchoRequest, 7, 1))
+	time.Sleep(20 * time.Millisecond)
+
+	if _, matched := tracker.Observe(buildV4Echo(t, dst, src, ICMPv4TypeEchoReply, 7, 1)); matched {
+		t.Fatal("expected stale request to have been evicted")
+	}
+}
+
+func TestICMPFlowTrackerResolvesEmbeddedError(t *testing.T) {
+	tracker := NewICMPFlowTracker(time.Second)
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+
+	tracker.Observe(buildV4Echo(t, src, dst, ICMPv4TypeEchoRequest, 99, 5))
+
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	origIP := &IPv4{Version: 4, TTL: 64, Protocol: IPProtocolICMPv4, SrcIP: src, DstIP: dst}
+	origICMP := &ICMPv4{TypeCode: CreateICMPv4TypeCode(ICMPv4TypeEchoRequest, 0), Id: 99, Seq: 5}
+	embeddedBuf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(embeddedBuf, opts, origIP, origICMP); err != nil {
+		t.Fatalf("serialize embedded: %v", err)
+	}
+
+	routerIP := &IPv4{Version: 4, TTL: 64, Protocol: IPProtocolICMPv4, SrcIP: net.IPv4(10, 0, 0, 254), DstIP: src}
+	unreachable := &ICMPv4{TypeCode: CreateICMPv4TypeCode(ICMPv4TypeDestinationUnreachable, 0)}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, opts, routerIP, unreachable, gopacket.Payload(embeddedBuf.Bytes())); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+	data := make([]byte, len(buf.Bytes()))
+	copy(data, buf.Bytes())
+
+	flow, matched := tracker.Observe(gopacket.NewPacket(data, LayerTypeIPv4, gopacket.Default))
+	if !matched {
+		t.Fatal("expected destination-unreachable to resolve the original flow")
+	}
+	if flow.Identifier != 99 {
+		t.Fatalf("unexpected flow %+v", flow)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2012 Google, Inc. All rights reserved.
+// Copyright 2009-2011 Andreas Krennmair. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/jamesfcarter/gopacket"
+)
+
+// IPPacketBuilder assembles a single IPv4 or IPv6 datagram from a payload
+// and a pair of endpoints, in one call, handling the total length,
+// identification, checksum and next-header bookkeeping that would
+// otherwise require hand-rolling gopacket.SerializeLayers with FixLengths
+// and ComputeChecksums. It's meant for userspace tunnels (TUN-backed
+// wireguard-style tools, QUIC-tunneled IP, etc.) that need to originate IP
+// packets rather than just decode them.
+//
+// The zero value is not usable; create one with NewIPPacketBuilder. An
+// IPPacketBuilder is not safe for concurrent use.
+type IPPacketBuilder struct {
+	SrcIP, DstIP net.IP
+	TTL          uint8
+	id           uint16
+}
+
+// NewIPPacketBuilder creates a builder for datagrams between src and dst.
+// Both IPs must be of the same family: both IPv4 (in either the 4-byte or
+// the 16-byte v4-in-v6 form) or both IPv6; ttl is used as the IPv4 TTL or IPv6 hop limit on every packet the
+// builder produces, and defaults to 64 if 0.
+func NewIPPacketBuilder(src, dst net.IP, ttl uint8) (*IPPacketBuilder, error) {
+	src4, dst4 := src.To4(), dst.To4()
+	switch {
+	case src4 != nil && dst4 != nil:
+		src, dst = src4, dst4
+	case src4 == nil && dst4 == nil && len(src) == net.IPv6len && len(dst) == net.IPv6len:
+	default:
+		return nil, fmt.Errorf("src and dst must both be IPv4 or both be IPv6, got %v and %v", src, dst)
+	}
+	if ttl == 0 {
+		ttl = 64
+	}
+	return &IPPacketBuilder{SrcIP: src, DstIP: dst, TTL: ttl}, nil
+}
+
+// Build serializes a complete IPv4 or IPv6 datagram carrying payload under
+// next-header protocol proto, filling in length, identification and
+// checksum fields automatically.
+func (b *IPPacketBuilder) Build(proto IPProtocol, payload []byte) ([]byte, error) {
+	b.id++
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	buf := gopacket.NewSerializeBuffer()
+	var top gopacket.SerializableLayer
+	switch len(b.SrcIP) {
+	case net.IPv4len:
+		top = &IPv4{Version: 4, TTL: b.TTL, Id: b.id, Protocol: proto, SrcIP: b.SrcIP, DstIP: b.DstIP}
+	case net.IPv6len:
+		top = &IPv6{Version: 6, HopLimit: b.TTL, NextHeader: proto, SrcIP: b.SrcIP, DstIP: b.DstIP}
+	default:
+		return nil, fmt.Errorf("invalid IP address length %d", len(b.SrcIP))
+	}
+	if err := gopacket.SerializeLayers(buf, opts, top, gopacket.Payload(payload)); err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(buf.Bytes()))
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+// IPPacket is the result of DecodeIPPacket: a parsed IPv4 or IPv6 datagram
+// with its ICMP layer, if any, already pulled out, so that callers don't
+// need to type-switch a generic gopacket.Packet themselves.
+type IPPacket struct {
+	IPv4    *IPv4
+	IPv6    *IPv6
+	ICMPv4  *ICMPv4
+	ICMPv6  *ICMPv6
+	Payload []byte
+}
+
+// DecodeIPPacket parses data as a version-dispatched raw IP datagram --
+// the same dispatch decodeIPv4or6 does -- but returns a typed IPPacket
+// instead of a generic gopacket.Packet.
+func DecodeIPPacket(data []byte) (*IPPacket, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty IP packet")
+	}
+	var lt gopacket.LayerType
+	switch data[0] >> 4 {
+	case 4:
+		lt = LayerTypeIPv4
+	case 6:
+		lt = LayerTypeIPv6
+	default:
+		return nil, fmt.Errorf("invalid IP packet version %v", data[0]>>4)
+	}
+	packet := gopacket.NewPacket(data, lt, gopacket.Default)
+	if errLayer := packet.ErrorLayer(); errLayer != nil {
+		return nil, errLayer.Error()
+	}
+	out := &IPPacket{}
+	if v4, ok := packet.Layer(LayerTypeIPv4).(*IPv4); ok {
+		out.IPv4 = v4
+	}
+	if v6, ok := packet.Layer(LayerTypeIPv6).(*IPv6); ok {
+		out.IPv6 = v6
+	}
+	if icmp4, ok := packet.Layer(LayerTypeICMPv4).(*ICMPv4); ok {
+		out.ICMPv4 = icmp4
+	}
+	if icmp6, ok := packet.Layer(LayerTypeICMPv6).(*ICMPv6); ok {
+		out.ICMPv6 = icmp6
+	}
+	if app := packet.ApplicationLayer(); app != nil {
+		out.Payload = app.Payload()
+	}
+	return out, nil
+}
+
+// ICMPPacket is a fully-built ICMP echo datagram -- v4 if SrcIP is an IPv4
+// address (in either 4-byte or 16-byte form), v6 otherwise -- ready to
+// serialize with Bytes.
+type ICMPPacket struct {
+	SrcIP, DstIP         net.IP
+	Identifier, Sequence uint16
+	Payload              []byte
+	reply                bool
+}
+
+// NewEchoRequest builds an ICMP echo request (v4 if src is an IPv4
+// address, v6 otherwise) with the given identifier, sequence number and
+// payload.
+func NewEchoRequest(src, dst net.IP, id, seq uint16, payload []byte) *ICMPPacket {
+	return &ICMPPacket{SrcIP: src, DstIP: dst, Identifier: id, Sequence: seq, Payload: payload}
+}
+
+// NewEchoReply builds the reply to req, swapping source and destination
+// and carrying the same identifier, sequence number and payload.
+func NewEchoReply(req *ICMPPacket) *ICMPPacket {
+	return &ICMPPacket{
+		SrcIP: req.DstIP, DstIP: req.SrcIP,
+		Identifier: req.Identifier, Sequence: req.Sequence,
+		Payload: req.Payload, reply: true,
+	}
+}
+
+// Bytes serializes p into a complete IP+ICMP datagram, computing the
+// ICMPv6 pseudo-header checksum against the IPv6 header where applicable.
+func (p *ICMPPacket) Bytes() ([]byte, error) {
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	buf := gopacket.NewSerializeBuffer()
+	// net.IPv4(...) and net.ParseIP("a.b.c.d") both return a 16-byte
+	// v4-in-v6-mapped slice, so normalize to the 4-byte form -- the way
+	// NewIPPacketBuilder does -- before dispatching on length.
+	if v4 := p.SrcIP.To4(); v4 != nil {
+		p.SrcIP = v4
+	}
+	if v4 := p.DstIP.To4(); v4 != nil {
+		p.DstIP = v4
+	}
+	switch len(p.SrcIP) {
+	case net.IPv4len:
+		ip := &IPv4{Version: 4, TTL: 64, Protocol: IPProtocolICMPv4, SrcIP: p.SrcIP, DstIP: p.DstIP}
+		typ := uint8(ICMPv4TypeEchoRequest)
+		if p.reply {
+			typ = ICMPv4TypeEchoReply
+		}
+		icmp := &ICMPv4{TypeCode: CreateICMPv4TypeCode(typ, 0), Id: p.Identifier, Seq: p.Sequence}
+		if err := gopacket.SerializeLayers(buf, opts, ip, icmp, gopacket.Payload(p.Payload)); err != nil {
+			return nil, err
+		}
+	case net.IPv6len:
+		ip := &IPv6{Version: 6, HopLimit: 64, NextHeader: IPProtocolICMPv6, SrcIP: p.SrcIP, DstIP: p.DstIP}
+		typ := uint8(ICMPv6TypeEchoRequest)
+		if p.reply {
+			typ = ICMPv6TypeEchoReply
+		}
+		icmp := &ICMPv6{TypeCode: CreateICMPv6TypeCode(typ, 0)}
+		icmp.SetNetworkLayerForChecksum(ip)
+		echo := &ICMPv6Echo{Identifier: p.Identifier, SeqNumber: p.Sequence}
+		if err := gopacket.SerializeLayers(buf, opts, ip, icmp, echo, gopacket.Payload(p.Payload)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid IP address length %d", len(p.SrcIP))
+	}
+	out := make([]byte, len(buf.Bytes()))
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+// IPFragmenter splits an oversized IPv4 or IPv6 datagram, as produced by
+// IPPacketBuilder or ICMPPacket, into a sequence of on-wire fragments no
+// larger than MTU: IPv4 fragments with the don't-fragment bit honored, or
+// IPv6 packets carrying a fragment extension header.
+//
+// The zero value is not usable; create one with NewIPFragmenter. An
+// IPFragmenter is not safe for concurrent use.
+type IPFragmenter struct {
+	MTU    int
+	nextID uint32
+}
+
+// NewIPFragmenter creates a fragmenter that targets the given MTU.
+func NewIPFragmenter(mtu int) *IPFragmenter {
+	return &IPFragmenter{MTU: mtu}
+}
+
+// Fragment splits datagram into a sequence of fragments no larger than the
+// fragmenter's MTU. If datagram already fits, it's returned unchanged as
+// the only element. An IPv4 datagram with the don't-fragment bit set that
+// doesn't fit returns an error instead of fragments.
+func (f *IPFragmenter) Fragment(datagram []byte) ([][]byte, error) {
+	if len(datagram) == 0 {
+		return nil, errors.New("empty datagram")
+	}
+	switch datagram[0] >> 4 {
+	case 4:
+		return f.fragmentV4(datagram)
+	case 6:
+		return f.fragmentV6(datagram)
+	}
+	return nil, fmt.Errorf("invalid IP packet version %v", datagram[0]>>4)
+}
+
+func (f *IPFragmenter) fragmentV4(datagram []byte) ([][]byte, error) {
+	pkt, err := DecodeIPPacket(datagram)
+	if err != nil {
+		return nil, err
+	}
+	if pkt.IPv4 == nil {
+		return nil, errors.New("not an IPv4 datagram")
+	}
+	ip := pkt.IPv4
+	if len(ip.Payload) <= f.MTU-len(ip.Contents) {
+		return [][]byte{datagram}, nil
+	}
+	if ip.Flags&IPv4DontFragment != 0 {
+		return nil, fmt.Errorf("datagram exceeds MTU %d and has don't-fragment set", f.MTU)
+	}
+	maxData := (f.MTU - len(ip.Contents)) &^ 7
+	if maxData <= 0 {
+		return nil, fmt.Errorf("MTU %d too small for a %d byte IPv4 header", f.MTU, len(ip.Contents))
+	}
+
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	var frags [][]byte
+	for offset := 0; offset < len(ip.Payload); offset += maxData {
+		end := offset + maxData
+		more := end < len(ip.Payload)
+		if !more {
+			end = len(ip.Payload)
+		}
+		frag := &IPv4{
+			Version:    4,
+			TTL:        ip.TTL,
+			Id:         ip.Id,
+			Protocol:   ip.Protocol,
+			FragOffset: uint16(offset / 8),
+			SrcIP:      ip.SrcIP,
+			DstIP:      ip.DstIP,
+		}
+		if more {
+			frag.Flags = IPv4MoreFragments
+		}
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, frag, gopacket.Payload(ip.Payload[offset:end])); err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(buf.Bytes()))
+		copy(out, buf.Bytes())
+		frags = append(frags, out)
+	}
+	return frags, nil
+}
+
+func (f *IPFragmenter) fragmentV6(datagram []byte) ([][]byte, error) {
+	pkt, err := DecodeIPPacket(datagram)
+	if err != nil {
+		return nil, err
+	}
+	if pkt.IPv6 == nil {
+		return nil, errors.New("not an IPv6 datagram")
+	}
+	ip := pkt.IPv6
+	if len(ip.Payload) <= f.MTU-len(ip.Contents) {
+		return [][]byte{datagram}, nil
+	}
+	const fragHeaderLen = 8
+	maxData := (f.MTU - len(ip.Contents) - fragHeaderLen) &^ 7
+	if maxData <= 0 {
+		return nil, fmt.Errorf("MTU %d too small for a %d byte IPv6 header plus fragment header", f.MTU, len(ip.Contents))
+	}
+
+	f.nextID++
+	id := f.nextID
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	var frags [][]byte
+	for offset := 0; offset < len(ip.Payload); offset += maxData {
+		end := offset + maxData
+		more := end < len(ip.Payload)
+		if !more {
+			end = len(ip.Payload)
+		}
+		frag := &IPv6{Version: 6, HopLimit: ip.HopLimit, NextHeader: IPProtocolIPv6Fragment, SrcIP: ip.SrcIP, DstIP: ip.DstIP}
+		fh := &IPv6Fragment{
+			NextHeader:     ip.NextHeader,
+			FragmentOffset: uint16(offset / 8),
+			MoreFragments:  more,
+			Identification: id,
+		}
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, frag, fh, gopacket.Payload(ip.Payload[offset:end])); err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(buf.Bytes()))
+		copy(out, buf.Bytes())
+		frags = append(frags, out)
+	}
+	return frags, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2012 Google, Inc. All rights reserved.
+// Copyright 2009-2011 Andreas Krennmair. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/jamesfcarter/gopacket"
+)
+
+func TestIPPacketBuilderRoundTripV4(t *testing.T) {
+	b, err := NewIPPacketBuilder(net.IPv4(192, 168, 0, 1), net.IPv4(192, 168, 0, 2), 0)
+	if err != nil {
+		t.Fatalf("NewIPPacketBuilder: %v", err)
+	}
+	payload := []byte("hello")
+	data, err := b.Build(IPProtocolUDP, payload)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkt, err := DecodeIPPacket(data)
+	if err != nil {
+		t.Fatalf("DecodeIPPacket: %v", err)
+	}
+	if pkt.IPv4 == nil {
+		t.Fatal("expected an IPv4 layer")
+	}
+	if !bytes.Equal(pkt.Payload, payload) {
+		t.Fatalf("payload mismatch: got %q want %q", pkt.Payload, payload)
+	}
+}
+
+func TestIPPacketBuilderRoundTripV6(t *testing.T) {
+	b, err := NewIPPacketBuilder(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), 0)
+	if err != nil {
+		t.Fatalf("NewIPPacketBuilder: %v", err)
+	}
+	data, err := b.Build(IPProtocolUDP, []byte("hello-v6"))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	pkt, err := DecodeIPPacket(data)
+	if err != nil {
+		t.Fatalf("DecodeIPPacket: %v", err)
+	}
+	if pkt.IPv6 == nil {
+		t.Fatal("expected an IPv6 layer")
+	}
+}
+
+func TestNewIPPacketBuilderRejectsMixedFamilies(t *testing.T) {
+	v4 := net.ParseIP("192.168.0.1")
+	v6 := net.ParseIP("2001:db8::1")
+	if _, err := NewIPPacketBuilder(v4, v6, 0); err == nil {
+		t.Fatal("expected an error mixing an IPv4 src with an IPv6 dst")
+	}
+	if _, err := NewIPPacketBuilder(v6, v4, 0); err == nil {
+		t.Fatal("expected an error mixing an IPv6 src with an IPv4 dst")
+	}
+}
+
+func TestEchoRequestReplyRoundTrip(t *testing.T) {
+	src := net.IPv4(10, 0, 0, 1)
+	dst := net.IPv4(10, 0, 0, 2)
+	req := NewEchoRequest(src, dst, 7, 1, []byte("ping"))
+	data, err := req.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	pkt, err := DecodeIPPacket(data)
+	if err != nil {
+		t.Fatalf("DecodeIPPacket: %v", err)
+	}
+	if pkt.ICMPv4 == nil || pkt.ICMPv4.Id != 7 || pkt.ICMPv4.Seq != 1 {
+		t.Fatalf("unexpected ICMPv4 layer: %+v", pkt.ICMPv4)
+	}
+
+	reply := NewEchoReply(req)
+	if !reply.SrcIP.Equal(dst) || !reply.DstIP.Equal(src) {
+		t.Fatalf("expected reply to swap src/dst, got src=%v dst=%v", reply.SrcIP, reply.DstIP)
+	}
+}
+
+func TestIPFragmenterSplitsOversizedDatagram(t *testing.T) {
+	b, err := NewIPPacketBuilder(net.IPv4(10, 0, 0, 1), net.IPv4(10, 0, 0, 2), 0)
+	if err != nil {
+		t.Fatalf("NewIPPacketBuilder: %v", err)
+	}
+	payload := bytes.Repeat([]byte{0xAB}, 3000)
+	data, err := b.Build(IPProtocolUDP, payload)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	frags, err := NewIPFragmenter(576).Fragment(data)
+	if err != nil {
+		t.Fatalf("Fragment: %v", err)
+	}
+	if len(frags) < 2 {
+		t.Fatalf("expected multiple fragments, got %d", len(frags))
+	}
+
+	var reassembled []byte
+	for _, frag := range frags {
+		pkt, err := DecodeIPPacket(frag)
+		if err != nil {
+			t.Fatalf("DecodeIPPacket(fragment): %v", err)
+		}
+		reassembled = append(reassembled, pkt.Payload...)
+	}
+	if !bytes.Equal(reassembled, payload) {
+		t.Fatal("reassembled fragment payloads did not match the original payload")
+	}
+}
+
+func TestIPFragmenterHonorsDontFragment(t *testing.T) {
+	ip := &IPv4{
+		Version: 4, TTL: 64, Protocol: IPProtocolUDP, Flags: IPv4DontFragment,
+		SrcIP: net.IPv4(10, 0, 0, 1), DstIP: net.IPv4(10, 0, 0, 2),
+	}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ip, gopacket.Payload(bytes.Repeat([]byte{0xCD}, 3000))); err != nil {
+		t.Fatalf("serialize: %v", err)
+	}
+
+	if _, err := NewIPFragmenter(576).Fragment(buf.Bytes()); err == nil {
+		t.Fatal("expected an error fragmenting a don't-fragment datagram that exceeds the MTU")
+	}
+}